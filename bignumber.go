@@ -0,0 +1,27 @@
+package rueidis
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// AsBigInt returns the value of a RESP3 big number reply ('(') as a
+// *big.Int, decoded losslessly regardless of how far it exceeds int64
+// range. This matters for modules and scripts that return counters or
+// cryptographic values beyond 2^63.
+func (m RedisMessage) AsBigInt() (*big.Int, error) {
+	if m.typ != '(' {
+		return nil, fmt.Errorf("rueidis: message type %c is not a big number", m.typ)
+	}
+	return m.bignum, nil
+}
+
+// AsBigFloat returns the value of a RESP3 big number reply ('(') as a
+// *big.Float.
+func (m RedisMessage) AsBigFloat() (*big.Float, error) {
+	v, err := m.AsBigInt()
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Float).SetInt(v), nil
+}
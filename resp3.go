@@ -2,45 +2,112 @@ package rueidis
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"math/big"
 	"strconv"
 	"strings"
+	"sync"
 	"unsafe"
 )
 
 var errChunked = errors.New("unbounded redis message")
 
+// ErrReplyTooLarge is returned by readB/readA when a declared length exceeds
+// the configured alloc limit. The offending bytes are drained off the wire
+// first, so the connection stays in sync and can be reused instead of torn
+// down.
+var ErrReplyTooLarge = errors.New("redis: reply too large")
+
+// ReadLimits bounds how many bytes readB will allocate up front for a
+// single blob string reply, and how many elements readA will allocate up
+// front for a single aggregate reply, so a corrupt or hostile length
+// prefix (e.g. "$9223372036854775000") can't OOM or panic the process. It
+// travels with each call through ReadNextMessageWithLimits rather than
+// living in mutable global state, so it can be wired per-connection (e.g.
+// from a future ClientOption field) instead of every client in a process
+// being stuck sharing one knob.
+type ReadLimits struct {
+	BlobString int64
+	Aggregate  int64
+}
+
+// DefaultReadLimits is what the unexported readNextMessage uses when no
+// caller threads its own ReadLimits through: 1 MiB per blob string, 1M
+// elements per aggregate.
+func DefaultReadLimits() ReadLimits {
+	return ReadLimits{BlobString: 1 << 20, Aggregate: 1 << 20}
+}
+
 type reader func(i *bufio.Reader) (RedisMessage, error)
 
-var readers = [256]reader{}
+// readersMu guards readers: RegisterReplyType can be called after startup
+// (the realistic case for a module adding a tag once it's loaded) while
+// readNextMessage looks readers up from every connection's read loop.
+var (
+	readersMu sync.RWMutex
+	readers   = [256]reader{}
+)
+
+// coreReplyTypes are the RESP2/RESP3 tags the parser handles itself, either
+// through the table wired up in init below or directly in
+// ReadNextMessageWithLimits. They can't be reassigned through
+// RegisterReplyType, so a module registration can never shadow the core
+// protocol by mistake.
+var coreReplyTypes = [256]bool{
+	'$': true, '+': true, '-': true, ':': true, '_': true, ',': true,
+	'#': true, '!': true, '=': true, '(': true, '*': true, '%': true,
+	'~': true, '|': true, '>': true, '.': true,
+}
 
+// $, !, =, *, ~, >, %, and | are handled directly by
+// ReadNextMessageWithLimits instead of going through this table, since
+// they're the tags ReadLimits applies to; everything else (including any
+// tag registered through RegisterReplyType) is dispatched from here.
 func init() {
-	readers['$'] = readBlobString
 	readers['+'] = readSimpleString
 	readers['-'] = readSimpleString
 	readers[':'] = readInteger
 	readers['_'] = readNull
 	readers[','] = readSimpleString
 	readers['#'] = readBoolean
-	readers['!'] = readBlobString
-	readers['='] = readBlobString
-	readers['('] = readSimpleString
-	readers['*'] = readArray
-	readers['%'] = readMap
-	readers['~'] = readArray
-	readers['|'] = readMap
-	readers['>'] = readArray
+	readers['('] = readBigNumber
 	readers['.'] = readNull
 }
 
+// RegisterReplyType teaches the parser about a first-byte discriminator
+// outside the core RESP2/RESP3 protocol, invoking fn whenever a reply
+// starting with tag is read. It's meant for module authors (RedisJSON,
+// RediSearch, RedisTimeSeries, ...) and applications built on custom Redis
+// forks that need rueidis to recognize their own reply tags without
+// forking the parser. Registering one of the core RESP tags fails with an
+// error instead of overwriting it.
+func RegisterReplyType(tag byte, fn func(i *bufio.Reader) (RedisMessage, error)) error {
+	if coreReplyTypes[tag] {
+		return fmt.Errorf("rueidis: reply type %q is a core RESP tag and can't be overridden", string(tag))
+	}
+	readersMu.Lock()
+	readers[tag] = fn
+	readersMu.Unlock()
+	return nil
+}
+
+func lookupReader(tag byte) reader {
+	readersMu.RLock()
+	fn := readers[tag]
+	readersMu.RUnlock()
+	return fn
+}
+
 func readSimpleString(i *bufio.Reader) (m RedisMessage, err error) {
 	m.string, err = readS(i)
 	return
 }
 
-func readBlobString(i *bufio.Reader) (m RedisMessage, err error) {
-	m.string, err = readB(i)
+func readBlobString(i *bufio.Reader, limit int64) (m RedisMessage, err error) {
+	m.string, err = readB(i, limit)
 	if err == errChunked {
 		sb := strings.Builder{}
 		for {
@@ -66,11 +133,48 @@ func readBlobString(i *bufio.Reader) (m RedisMessage, err error) {
 	return
 }
 
+// readVerbatimString handles the RESP3 '=' frame. Unlike a plain blob
+// string, its payload carries a mandatory 3-byte encoding prefix (e.g.
+// "txt:", "mkd:") ahead of the actual text, which readBlobString would
+// otherwise fold silently into m.string. The prefix is split off into
+// m.format so AsVerbatimString can hand it back to the caller.
+func readVerbatimString(i *bufio.Reader, limit int64) (m RedisMessage, err error) {
+	s, err := readB(i, limit)
+	if err != nil {
+		return RedisMessage{}, err
+	}
+	if len(s) < 4 || s[3] != ':' {
+		return RedisMessage{}, fmt.Errorf("rueidis: received verbatim string without a 3-byte format prefix: %q", s)
+	}
+	m.format = s[:3]
+	m.string = s[4:]
+	return m, nil
+}
+
 func readInteger(i *bufio.Reader) (m RedisMessage, err error) {
 	m.integer, err = readI(i)
 	return
 }
 
+// readBigNumber handles the RESP3 '(' frame. Its payload can exceed int64
+// range, so unlike readInteger it parses straight into a *big.Int kept on
+// the message instead of losing precision through m.integer; AsBigInt and
+// AsBigFloat hand that value back without the caller having to reparse
+// m.string itself.
+func readBigNumber(i *bufio.Reader) (m RedisMessage, err error) {
+	s, err := readS(i)
+	if err != nil {
+		return RedisMessage{}, err
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return RedisMessage{}, fmt.Errorf("rueidis: received malformed big number: %q", s)
+	}
+	m.string = s
+	m.bignum = v
+	return m, nil
+}
+
 func readBoolean(i *bufio.Reader) (m RedisMessage, err error) {
 	b, err := i.ReadByte()
 	if err != nil {
@@ -88,30 +192,17 @@ func readNull(i *bufio.Reader) (m RedisMessage, err error) {
 	return
 }
 
-func readArray(i *bufio.Reader) (m RedisMessage, err error) {
-	length, err := readI(i)
-	if err == errChunked {
-		m.values, err = readE(i)
-	} else {
-		m.values, err = readA(i, int(length))
-	}
-	if err != nil {
-		return RedisMessage{}, err
-	}
-	return
-}
-
-func readMap(i *bufio.Reader) (m RedisMessage, err error) {
+// readAggregate reads the body of a '*'/'~'/'>' array (factor 1) or a
+// '%'/'|' map (factor 2, since each key/value pair counts as two elements).
+func readAggregate(i *bufio.Reader, lim ReadLimits, factor int) ([]RedisMessage, error) {
 	length, err := readI(i)
 	if err == errChunked {
-		m.values, err = readE(i)
-	} else {
-		m.values, err = readA(i, int(length*2))
+		return readE(i, lim)
 	}
 	if err != nil {
-		return RedisMessage{}, err
+		return nil, err
 	}
-	return
+	return readA(i, int(length)*factor, lim)
 }
 
 func readS(i *bufio.Reader) (string, error) {
@@ -158,42 +249,94 @@ func readI(i *bufio.Reader) (int64, error) {
 	}
 }
 
-func readB(i *bufio.Reader) (string, error) {
+func readB(i *bufio.Reader, limit int64) (string, error) {
 	length, err := readI(i)
 	if err != nil {
 		return "", err
 	}
-	bs := make([]byte, length)
-	if _, err = io.ReadFull(i, bs); err != nil {
-		return "", err
+	if length <= limit {
+		bs := make([]byte, length)
+		if _, err = io.ReadFull(i, bs); err != nil {
+			return "", err
+		}
+		if _, err = i.Discard(2); err != nil {
+			return "", err
+		}
+		return *(*string)(unsafe.Pointer(&bs)), nil
+	}
+	// The declared length blows past the guard: drain it in bounded chunks
+	// instead of trusting it with a single make([]byte, length), so the
+	// reader stays in sync with the stream and the connection can be reused.
+	buf := bytes.Buffer{}
+	buf.Grow(int(limit))
+	for remaining := length; remaining > 0; {
+		n := remaining
+		if n > limit {
+			n = limit
+		}
+		buf.Reset()
+		if _, err = io.CopyN(&buf, i, n); err != nil {
+			return "", err
+		}
+		remaining -= n
 	}
 	if _, err = i.Discard(2); err != nil {
 		return "", err
 	}
-	return *(*string)(unsafe.Pointer(&bs)), nil
+	return "", ErrReplyTooLarge
 }
 
-func readE(i *bufio.Reader) ([]RedisMessage, error) {
+func readE(i *bufio.Reader, lim ReadLimits) ([]RedisMessage, error) {
 	v := make([]RedisMessage, 0)
+	var tooLarge bool
 	for {
-		n, err := readNextMessage(i)
+		n, err := ReadNextMessageWithLimits(i, lim)
+		if err == ErrReplyTooLarge {
+			// The oversized element is already drained; keep reading up to
+			// the '.' terminator so the whole aggregate leaves the wire.
+			tooLarge = true
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
 		if n.typ == '.' {
+			if tooLarge {
+				return nil, ErrReplyTooLarge
+			}
 			return v, err
 		}
 		v = append(v, n)
 	}
 }
 
-func readA(i *bufio.Reader, length int) (v []RedisMessage, err error) {
+func readA(i *bufio.Reader, length int, lim ReadLimits) (v []RedisMessage, err error) {
+	if int64(length) > lim.Aggregate {
+		// Same reasoning as readB's overflow path: still read (and discard)
+		// every declared element so the stream stays aligned for whatever
+		// comes next, but never commit to a make([]RedisMessage, length) of
+		// attacker-controlled size.
+		for n := 0; n < length; n++ {
+			if _, err = ReadNextMessageWithLimits(i, lim); err != nil && err != ErrReplyTooLarge {
+				return nil, err
+			}
+		}
+		return nil, ErrReplyTooLarge
+	}
 	v = make([]RedisMessage, length)
+	var tooLarge bool
 	for n := 0; n < length; n++ {
-		if v[n], err = readNextMessage(i); err != nil {
+		if v[n], err = ReadNextMessageWithLimits(i, lim); err == ErrReplyTooLarge {
+			// The oversized element is already drained; keep reading its
+			// siblings so the whole aggregate leaves the wire.
+			tooLarge = true
+		} else if err != nil {
 			return nil, err
 		}
 	}
+	if tooLarge {
+		return nil, ErrReplyTooLarge
+	}
 	return v, nil
 }
 
@@ -211,19 +354,50 @@ func writeS(o *bufio.Writer, id byte, str string) (err error) {
 	return err
 }
 
+// readNextMessage reads the next reply using DefaultReadLimits. It exists
+// so call sites that don't yet have a ReadLimits of their own to thread
+// through keep working unchanged.
 func readNextMessage(i *bufio.Reader) (m RedisMessage, err error) {
+	return ReadNextMessageWithLimits(i, DefaultReadLimits())
+}
+
+// ReadNextMessageWithLimits reads the next reply off i, applying lim to any
+// blob string or aggregate it decodes (including nested ones). This is the
+// entry point per-connection alloc-limit wiring (e.g. from a future
+// ClientOption) should call instead of the fixed-default readNextMessage.
+func ReadNextMessageWithLimits(i *bufio.Reader, lim ReadLimits) (m RedisMessage, err error) {
 	var attrs *RedisMessage
 	var typ byte
 	for {
 		if typ, err = i.ReadByte(); err != nil {
 			return RedisMessage{}, err
 		}
-		fn := readers[typ]
-		if fn == nil {
-			typStr := string(typ)
-			panic(unknownMessageType + typStr)
+		switch typ {
+		case '$', '!':
+			m, err = readBlobString(i, lim.BlobString)
+		case '=':
+			m, err = readVerbatimString(i, lim.BlobString)
+		case '*', '~', '>':
+			m.values, err = readAggregate(i, lim, 1)
+		case '%', '|':
+			m.values, err = readAggregate(i, lim, 2)
+		default:
+			fn := lookupReader(typ)
+			if fn == nil {
+				typStr := string(typ)
+				panic(unknownMessageType + typStr)
+			}
+			m, err = fn(i)
 		}
-		if m, err = fn(i); err != nil {
+		if err == ErrReplyTooLarge && typ == '|' {
+			// The attributes are already drained; drain the reply they
+			// annotate as well, so the next read starts at a fresh reply.
+			if _, err = ReadNextMessageWithLimits(i, lim); err != nil && err != ErrReplyTooLarge {
+				return RedisMessage{}, err
+			}
+			return RedisMessage{}, ErrReplyTooLarge
+		}
+		if err != nil {
 			return RedisMessage{}, err
 		}
 		m.typ = typ
@@ -0,0 +1,65 @@
+package rueidis
+
+import (
+	"bufio"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReadNextMessageWithLimitsDrainsOversizedBlobString(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("$10\r\n0123456789\r\n+OK\r\n"))
+	_, err := ReadNextMessageWithLimits(r, ReadLimits{BlobString: 4, Aggregate: 4})
+	if err != ErrReplyTooLarge {
+		t.Fatalf("got %v, want ErrReplyTooLarge", err)
+	}
+	// The oversized reply's bytes must have been fully drained so the next
+	// reply on the wire is still readable.
+	m, err := readNextMessage(r)
+	if err != nil {
+		t.Fatalf("unexpected error reading the following reply: %v", err)
+	}
+	if m.string != "OK" {
+		t.Fatalf("got %q, want %q", m.string, "OK")
+	}
+}
+
+func TestReadNextMessageWithLimitsDrainsOversizedArray(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("*3\r\n:1\r\n:2\r\n:3\r\n+OK\r\n"))
+	_, err := ReadNextMessageWithLimits(r, ReadLimits{BlobString: 1 << 20, Aggregate: 2})
+	if err != ErrReplyTooLarge {
+		t.Fatalf("got %v, want ErrReplyTooLarge", err)
+	}
+	m, err := readNextMessage(r)
+	if err != nil {
+		t.Fatalf("unexpected error reading the following reply: %v", err)
+	}
+	if m.string != "OK" {
+		t.Fatalf("got %q, want %q", m.string, "OK")
+	}
+}
+
+func TestReadNextMessageWithLimitsIsPerCall(t *testing.T) {
+	// Two "connections" with different limits, read concurrently, must not
+	// bleed into each other the way a shared global would.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var strictErr, relaxedErr error
+	go func() {
+		defer wg.Done()
+		r := bufio.NewReader(strings.NewReader("$10\r\n0123456789\r\n"))
+		_, strictErr = ReadNextMessageWithLimits(r, ReadLimits{BlobString: 1})
+	}()
+	go func() {
+		defer wg.Done()
+		r := bufio.NewReader(strings.NewReader("$10\r\n0123456789\r\n"))
+		_, relaxedErr = ReadNextMessageWithLimits(r, ReadLimits{BlobString: 1 << 20})
+	}()
+	wg.Wait()
+	if strictErr != ErrReplyTooLarge {
+		t.Fatalf("strict reader: got %v, want ErrReplyTooLarge", strictErr)
+	}
+	if relaxedErr != nil {
+		t.Fatalf("relaxed reader: unexpected error: %v", relaxedErr)
+	}
+}
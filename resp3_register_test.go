@@ -0,0 +1,50 @@
+package rueidis
+
+import (
+	"bufio"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRegisterReplyTypeRejectsCoreTags(t *testing.T) {
+	if err := RegisterReplyType('$', func(*bufio.Reader) (RedisMessage, error) {
+		return RedisMessage{}, nil
+	}); err == nil {
+		t.Fatal("expected an error overriding a core RESP tag")
+	}
+}
+
+func TestRegisterReplyTypeDispatchesThroughReadNextMessage(t *testing.T) {
+	if err := RegisterReplyType('J', func(i *bufio.Reader) (RedisMessage, error) {
+		return readSimpleString(i)
+	}); err != nil {
+		t.Fatalf("unexpected error registering tag: %v", err)
+	}
+	r := bufio.NewReader(strings.NewReader("Jhello\r\n"))
+	m, err := readNextMessage(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.string != "hello" {
+		t.Fatalf("got %q, want %q", m.string, "hello")
+	}
+}
+
+func TestRegisterReplyTypeConcurrentWithLookup(t *testing.T) {
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = RegisterReplyType('J', func(i *bufio.Reader) (RedisMessage, error) {
+				return readSimpleString(i)
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = lookupReader('J')
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,259 @@
+package rueidis
+
+import (
+	"encoding"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scan decodes the message into dest via reflection. dest must be a
+// non-nil pointer to a string, []byte, sized int/uint/float, bool,
+// time.Time, time.Duration, encoding.TextUnmarshaler/BinaryUnmarshaler, a
+// slice of any of those, or a map/struct with string keys (matched against
+// struct fields by their `redis:"name"` tag, falling back to the field
+// name).
+func (m RedisMessage) Scan(dest interface{}) error {
+	return Scan(m, dest)
+}
+
+// Scan is the package-level equivalent of RedisMessage.Scan, for callers
+// that have a RedisMessage value in hand but would rather not spell out the
+// method-call form (e.g. when passing the function itself around).
+func Scan(m RedisMessage, dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("rueidis: Scan(non-pointer %T)", dest)
+	}
+	return scanInto(m, rv.Elem())
+}
+
+// scanInto is the package-level helper behind RedisMessage.Scan. It is kept
+// separate from the method so slice/map/struct decoding can recurse into it
+// without re-deriving an addressable reflect.Value each time.
+func scanInto(m RedisMessage, rv reflect.Value) error {
+	if rv.CanAddr() {
+		switch ptr := rv.Addr().Interface().(type) {
+		case *time.Time:
+			return scanTime(m, ptr)
+		case *time.Duration:
+			return scanDuration(m, ptr)
+		case *big.Int:
+			return scanBigInt(m, ptr)
+		case *big.Float:
+			return scanBigFloat(m, ptr)
+		case *big.Rat:
+			return scanBigRat(m, ptr)
+		case encoding.TextUnmarshaler:
+			return ptr.UnmarshalText([]byte(m.string))
+		case encoding.BinaryUnmarshaler:
+			return ptr.UnmarshalBinary([]byte(m.string))
+		}
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(m.string)
+	case reflect.Bool:
+		rv.SetBool(m.integer != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		iv, err := scanInt(m)
+		if err != nil {
+			return err
+		}
+		rv.SetInt(iv)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uv, err := scanUint(m)
+		if err != nil {
+			return err
+		}
+		rv.SetUint(uv)
+	case reflect.Float32, reflect.Float64:
+		fv, err := scanFloat(m)
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(fv)
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			rv.SetBytes([]byte(m.string))
+			return nil
+		}
+		return scanSlice(m, rv)
+	case reflect.Map:
+		return scanMap(m, rv)
+	case reflect.Struct:
+		return scanStruct(m, rv)
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return scanInto(m, rv.Elem())
+	default:
+		return fmt.Errorf("rueidis: Scan: unsupported destination kind %s", rv.Kind())
+	}
+	return nil
+}
+
+func scanInt(m RedisMessage) (int64, error) {
+	if m.typ == ':' || m.typ == '#' {
+		return m.integer, nil
+	}
+	if m.typ == '(' {
+		if !m.bignum.IsInt64() {
+			return 0, fmt.Errorf("rueidis: Scan: big number %s overflows int64", m.bignum.String())
+		}
+		return m.bignum.Int64(), nil
+	}
+	return strconv.ParseInt(strings.TrimSpace(m.string), 10, 64)
+}
+
+func scanUint(m RedisMessage) (uint64, error) {
+	if m.typ == ':' || m.typ == '#' {
+		return uint64(m.integer), nil
+	}
+	if m.typ == '(' {
+		if !m.bignum.IsUint64() {
+			return 0, fmt.Errorf("rueidis: Scan: big number %s overflows uint64", m.bignum.String())
+		}
+		return m.bignum.Uint64(), nil
+	}
+	return strconv.ParseUint(strings.TrimSpace(m.string), 10, 64)
+}
+
+func scanFloat(m RedisMessage) (float64, error) {
+	if m.typ == ':' {
+		return float64(m.integer), nil
+	}
+	if m.typ == '(' {
+		f, _ := new(big.Float).SetInt(m.bignum).Float64()
+		return f, nil
+	}
+	return strconv.ParseFloat(strings.TrimSpace(m.string), 64)
+}
+
+func scanTime(m RedisMessage, dst *time.Time) error {
+	if m.typ == ':' {
+		*dst = time.Unix(m.integer, 0)
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, m.string); err == nil {
+		*dst = t
+		return nil
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(m.string), 10, 64)
+	if err != nil {
+		return fmt.Errorf("rueidis: Scan: cannot parse %q as time.Time", m.string)
+	}
+	*dst = time.Unix(sec, 0)
+	return nil
+}
+
+func scanDuration(m RedisMessage, dst *time.Duration) error {
+	if m.typ == ':' {
+		*dst = time.Duration(m.integer) * time.Second
+		return nil
+	}
+	if d, err := time.ParseDuration(m.string); err == nil {
+		*dst = d
+		return nil
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(m.string), 10, 64)
+	if err != nil {
+		return fmt.Errorf("rueidis: Scan: cannot parse %q as time.Duration", m.string)
+	}
+	*dst = time.Duration(sec) * time.Second
+	return nil
+}
+
+func scanBigInt(m RedisMessage, dst *big.Int) error {
+	if m.typ == '(' {
+		dst.Set(m.bignum)
+		return nil
+	}
+	if _, ok := dst.SetString(strings.TrimSpace(m.string), 10); !ok {
+		return fmt.Errorf("rueidis: Scan: cannot parse %q as big.Int", m.string)
+	}
+	return nil
+}
+
+func scanBigFloat(m RedisMessage, dst *big.Float) error {
+	if m.typ == '(' {
+		dst.SetInt(m.bignum)
+		return nil
+	}
+	if _, ok := dst.SetString(strings.TrimSpace(m.string)); !ok {
+		return fmt.Errorf("rueidis: Scan: cannot parse %q as big.Float", m.string)
+	}
+	return nil
+}
+
+func scanBigRat(m RedisMessage, dst *big.Rat) error {
+	if m.typ == '(' {
+		dst.SetInt(m.bignum)
+		return nil
+	}
+	if _, ok := dst.SetString(strings.TrimSpace(m.string)); !ok {
+		return fmt.Errorf("rueidis: Scan: cannot parse %q as big.Rat", m.string)
+	}
+	return nil
+}
+
+func scanSlice(m RedisMessage, rv reflect.Value) error {
+	out := reflect.MakeSlice(rv.Type(), len(m.values), len(m.values))
+	for i, e := range m.values {
+		if err := scanInto(e, out.Index(i)); err != nil {
+			return err
+		}
+	}
+	rv.Set(out)
+	return nil
+}
+
+func scanMap(m RedisMessage, rv reflect.Value) error {
+	kt := rv.Type().Key()
+	if kt.Kind() != reflect.String {
+		return fmt.Errorf("rueidis: Scan: map key must be string, got %s", kt)
+	}
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(rv.Type(), len(m.values)/2))
+	}
+	for i := 0; i+1 < len(m.values); i += 2 {
+		ev := reflect.New(rv.Type().Elem()).Elem()
+		if err := scanInto(m.values[i+1], ev); err != nil {
+			return err
+		}
+		rv.SetMapIndex(reflect.ValueOf(m.values[i].string).Convert(kt), ev)
+	}
+	return nil
+}
+
+func scanStruct(m RedisMessage, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i+1 < len(m.values); i += 2 {
+		name := m.values[i].string
+		for f := 0; f < rt.NumField(); f++ {
+			field := rt.Field(f)
+			if field.PkgPath != "" {
+				continue // unexported field: can't be used or set through reflection, skip it
+			}
+			tag := field.Tag.Get("redis")
+			if tag == "-" {
+				continue
+			}
+			if tag == "" {
+				tag = field.Name
+			}
+			if !strings.EqualFold(tag, name) {
+				continue
+			}
+			if err := scanInto(m.values[i+1], rv.Field(f)); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
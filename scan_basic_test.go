@@ -0,0 +1,77 @@
+package rueidis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanBasicKinds(t *testing.T) {
+	var s string
+	if err := (RedisMessage{typ: '$', string: "hello"}).Scan(&s); err != nil {
+		t.Fatalf("string: unexpected error: %v", err)
+	}
+	if s != "hello" {
+		t.Fatalf("string: got %q, want %q", s, "hello")
+	}
+
+	var i int
+	if err := (RedisMessage{typ: ':', integer: 42}).Scan(&i); err != nil {
+		t.Fatalf("int: unexpected error: %v", err)
+	}
+	if i != 42 {
+		t.Fatalf("int: got %d, want %d", i, 42)
+	}
+
+	var f float64
+	if err := (RedisMessage{typ: '$', string: "3.5"}).Scan(&f); err != nil {
+		t.Fatalf("float: unexpected error: %v", err)
+	}
+	if f != 3.5 {
+		t.Fatalf("float: got %v, want %v", f, 3.5)
+	}
+
+	var b bool
+	if err := (RedisMessage{typ: '#', integer: 1}).Scan(&b); err != nil {
+		t.Fatalf("bool: unexpected error: %v", err)
+	}
+	if !b {
+		t.Fatal("bool: got false, want true")
+	}
+
+	var sl []int64
+	m := RedisMessage{typ: '*', values: []RedisMessage{
+		{typ: ':', integer: 1},
+		{typ: ':', integer: 2},
+		{typ: ':', integer: 3},
+	}}
+	if err := m.Scan(&sl); err != nil {
+		t.Fatalf("slice: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(sl, []int64{1, 2, 3}) {
+		t.Fatalf("slice: got %v, want %v", sl, []int64{1, 2, 3})
+	}
+
+	var mp map[string]string
+	mm := RedisMessage{typ: '%', values: []RedisMessage{
+		{typ: '$', string: "a"},
+		{typ: '$', string: "1"},
+		{typ: '$', string: "b"},
+		{typ: '$', string: "2"},
+	}}
+	if err := mm.Scan(&mp); err != nil {
+		t.Fatalf("map: unexpected error: %v", err)
+	}
+	if mp["a"] != "1" || mp["b"] != "2" {
+		t.Fatalf("map: got %v, want a=1 b=2", mp)
+	}
+}
+
+func TestScanPackageLevelHelper(t *testing.T) {
+	var s string
+	if err := Scan(RedisMessage{typ: '$', string: "world"}, &s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s != "world" {
+		t.Fatalf("got %q, want %q", s, "world")
+	}
+}
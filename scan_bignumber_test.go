@@ -0,0 +1,26 @@
+package rueidis
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestScanBigNumberIntoPlainInt(t *testing.T) {
+	m := RedisMessage{typ: '(', bignum: big.NewInt(12345)}
+	var got int64
+	if err := m.Scan(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 12345 {
+		t.Fatalf("got %d, want 12345", got)
+	}
+}
+
+func TestScanBigNumberOverflowsInt64(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	m := RedisMessage{typ: '(', bignum: huge}
+	var got int64
+	if err := m.Scan(&got); err == nil {
+		t.Fatal("expected an overflow error, got nil")
+	}
+}
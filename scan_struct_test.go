@@ -0,0 +1,23 @@
+package rueidis
+
+import "testing"
+
+func TestScanStructSkipsUnexportedFields(t *testing.T) {
+	type withUnexported struct {
+		mu     int // collides with the key name below
+		Public string
+	}
+	m := RedisMessage{typ: '%', values: []RedisMessage{
+		{typ: '$', string: "mu"},
+		{typ: '$', string: "locked"},
+		{typ: '$', string: "Public"},
+		{typ: '$', string: "value"},
+	}}
+	var dst withUnexported
+	if err := m.Scan(&dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dst.Public != "value" {
+		t.Fatalf("got %q, want %q", dst.Public, "value")
+	}
+}
@@ -0,0 +1,69 @@
+package rueidis
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// StreamAggregate decodes an aggregate reply ('*', '~', '>', '%', '|')
+// without materializing it into a []RedisMessage: fn is called once with
+// the declared number of sub-messages to expect (doubled for maps), or
+// repeatedly with n=1 for a chunked ('?') aggregate until the terminating
+// '.' sentinel. Returns an error, rather than panicking, if the reply turns
+// out not to be an aggregate at all.
+func StreamAggregate(i *bufio.Reader, fn func(r *bufio.Reader, n int64) error) (err error) {
+	typ, err := i.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch typ {
+	case '*', '~', '>':
+		return streamAggregate(i, fn, 1)
+	case '%', '|':
+		return streamAggregate(i, fn, 2)
+	default:
+		// A non-aggregate reply, e.g. a "-WRONGTYPE ..." error for the
+		// command in question. Put the tag back and decode the whole reply
+		// with readNextMessage so the stream stays in sync, then report it
+		// as an error rather than crashing the caller. A tag that isn't a
+		// recognized RESP tag at all means the wire itself is corrupt, and
+		// readNextMessage panics on it as the rest of the parser does.
+		if err = i.UnreadByte(); err != nil {
+			return err
+		}
+		m, err := readNextMessage(i)
+		if err != nil {
+			return err
+		}
+		if typ == '-' {
+			return fmt.Errorf("redis: %s", m.string)
+		}
+		return fmt.Errorf("rueidis: StreamAggregate: reply type %q is not an aggregate", string(typ))
+	}
+}
+
+func streamAggregate(i *bufio.Reader, fn func(r *bufio.Reader, n int64) error, factor int64) error {
+	length, err := readI(i)
+	if err == errChunked {
+		for {
+			b, peekErr := i.Peek(1)
+			if peekErr != nil {
+				return peekErr
+			}
+			if b[0] == '.' {
+				if _, err = i.Discard(1); err != nil {
+					return err
+				}
+				_, err = i.Discard(2)
+				return err
+			}
+			if err = fn(i, 1); err != nil {
+				return err
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return fn(i, length*factor)
+}
@@ -0,0 +1,83 @@
+package rueidis
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestStreamAggregateRejectsNonAggregateReply(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("-WRONGTYPE Operation against a key\r\n"))
+	err := StreamAggregate(r, func(*bufio.Reader, int64) error { return nil })
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if !strings.Contains(err.Error(), "WRONGTYPE") {
+		t.Fatalf("expected the server error text to surface, got: %v", err)
+	}
+}
+
+func TestStreamAggregateFixedLengthArray(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("*2\r\n:1\r\n:2\r\n"))
+	var gotN int64
+	var got []int64
+	err := StreamAggregate(r, func(rd *bufio.Reader, n int64) error {
+		gotN = n
+		for i := int64(0); i < n; i++ {
+			m, err := readNextMessage(rd)
+			if err != nil {
+				return err
+			}
+			got = append(got, m.integer)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotN != 2 {
+		t.Fatalf("got n=%d, want 2", gotN)
+	}
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("unexpected elements: %v", got)
+	}
+}
+
+func TestStreamAggregateMapDoublesLength(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("%2\r\n$1\r\na\r\n:1\r\n$1\r\nb\r\n:2\r\n"))
+	var gotN int64
+	err := StreamAggregate(r, func(rd *bufio.Reader, n int64) error {
+		gotN = n
+		for i := int64(0); i < n; i++ {
+			if _, err := readNextMessage(rd); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotN != 4 {
+		t.Fatalf("got n=%d, want 4 (2 pairs doubled)", gotN)
+	}
+}
+
+func TestStreamAggregateChunked(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("*?\r\n:1\r\n:2\r\n.\r\n"))
+	var got []int64
+	err := StreamAggregate(r, func(rd *bufio.Reader, n int64) error {
+		m, err := readNextMessage(rd)
+		if err != nil {
+			return err
+		}
+		got = append(got, m.integer)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("unexpected elements: %v", got)
+	}
+}
@@ -0,0 +1,15 @@
+package rueidis
+
+import "fmt"
+
+// AsVerbatimString returns the text and 3-byte format tag (e.g. "txt",
+// "mkd") of a RESP3 verbatim string reply ('='). Commands such as
+// CLIENT INFO or LATENCY DOCTOR return this type, which lets callers tell
+// a Markdown-formatted reply apart from an ordinary bulk string and render
+// it accordingly.
+func (m RedisMessage) AsVerbatimString() (text string, format string, err error) {
+	if m.typ != '=' {
+		return "", "", fmt.Errorf("rueidis: message type %c is not a verbatim string", m.typ)
+	}
+	return m.string, m.format, nil
+}
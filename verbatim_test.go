@@ -0,0 +1,30 @@
+package rueidis
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestReadVerbatimStringMalformedPrefixReturnsError(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("=2\r\nhi\r\n"))
+	_, err := readNextMessage(r)
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+}
+
+func TestReadVerbatimStringWellFormed(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("=9\r\ntxt:hello\r\n"))
+	m, err := readNextMessage(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text, format, err := m.AsVerbatimString()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "hello" || format != "txt" {
+		t.Fatalf("got text=%q format=%q, want text=%q format=%q", text, format, "hello", "txt")
+	}
+}